@@ -0,0 +1,199 @@
+package netpipetestserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Network is a small in-memory network of named hosts, each of which may
+// register listeners on ports and be dialed by other hosts using
+// "host:port" addresses, entirely without opening real sockets, so that
+// tests involving several servers talking to each other can run inside a
+// synctest bubble.
+type Network struct {
+	acceptQueueDepth int
+
+	mu    sync.Mutex
+	hosts map[string]*networkHost
+}
+
+// NewNetwork creates an empty Network. acceptQueueDepth controls how many
+// pending connections each host's listeners will buffer before a Dial on
+// that listener blocks waiting for a matching Accept.
+func NewNetwork(acceptQueueDepth int) *Network {
+	return &Network{
+		acceptQueueDepth: acceptQueueDepth,
+		hosts:            make(map[string]*networkHost),
+	}
+}
+
+// Host returns the named host, creating it the first time it is requested.
+func (n *Network) Host(name string) *Host {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	h, ok := n.hosts[name]
+	if !ok {
+		h = &networkHost{
+			network:   n,
+			name:      name,
+			listeners: make(map[string]*networkListener),
+		}
+		n.hosts[name] = h
+	}
+	return &Host{h}
+}
+
+// dial connects to the listener registered at addr (a "host:port" string)
+// on the Network, returning the client side of the resulting connection.
+func (n *Network) dial(ctx context.Context, addr string) (net.Conn, error) {
+	hostName, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: err}
+	}
+
+	n.mu.Lock()
+	h, ok := n.hosts[hostName]
+	n.mu.Unlock()
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Addr: networkAddr{hostName, port}, Err: errors.New("no such host")}
+	}
+
+	h.mu.Lock()
+	ln, ok := h.listeners[port]
+	h.mu.Unlock()
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Addr: networkAddr{hostName, port}, Err: errors.New("connection refused")}
+	}
+
+	clientConn, serverConn := net.Pipe()
+	select {
+	case ln.connCh <- serverConn:
+		return clientConn, nil
+	case <-ln.done:
+		clientConn.Close()
+		serverConn.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		clientConn.Close()
+		serverConn.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// Host is a single participant in a Network, identified by name, that can
+// register listeners other hosts reach by dialing "host:port" addresses.
+type Host struct {
+	*networkHost
+}
+
+type networkHost struct {
+	network *Network
+	name    string
+
+	mu        sync.Mutex
+	listeners map[string]*networkListener
+}
+
+// Listen registers a listener for the host on the given port. Other hosts
+// on the Network connect to it by dialing "host:port".
+func (h *networkHost) Listen(port string) (net.Listener, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	addr := networkAddr{h.name, port}
+	if _, ok := h.listeners[port]; ok {
+		return nil, &net.OpError{Op: "listen", Net: "tcp", Addr: addr, Err: errors.New("address already in use")}
+	}
+
+	ln := &networkListener{
+		host:   h,
+		port:   port,
+		addr:   addr,
+		connCh: make(chan net.Conn, h.network.acceptQueueDepth),
+		done:   make(chan struct{}),
+	}
+	h.listeners[port] = ln
+	return ln, nil
+}
+
+// Dial connects to the listener registered at addr (a "host:port" string)
+// elsewhere on the Network.
+func (h *networkHost) Dial(addr string) (net.Conn, error) {
+	return h.network.dial(context.Background(), addr)
+}
+
+// networkListener is a [net.Listener] backed by a host's entry in a
+// Network, delivering connections dialed by other hosts via [net.Pipe].
+type networkListener struct {
+	host *networkHost
+	port string
+	addr networkAddr
+
+	connCh chan net.Conn
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (ln *networkListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-ln.connCh:
+		return conn, nil
+	case <-ln.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (ln *networkListener) Close() error {
+	ln.closeOnce.Do(func() {
+		close(ln.done)
+		ln.host.mu.Lock()
+		delete(ln.host.listeners, ln.port)
+		ln.host.mu.Unlock()
+	})
+	return nil
+}
+
+func (ln *networkListener) Addr() net.Addr {
+	return ln.addr
+}
+
+type networkAddr struct {
+	host string
+	port string
+}
+
+func (a networkAddr) Network() string { return "tcp" }
+func (a networkAddr) String() string  { return net.JoinHostPort(a.host, a.port) }
+
+var _ net.Addr = networkAddr{}
+
+// NewServer creates a new httptest.Server and http.Client pair hosted on
+// network at host:port. The returned client's transport resolves the host
+// in any request URL through network, so it can also reach any other host
+// previously registered on it.
+func NewServer(t *testing.T, network *Network, host, port string, handler http.Handler) (*httptest.Server, *http.Client) {
+	t.Helper()
+
+	ln, err := network.Host(host).Listen(port)
+	if err != nil {
+		t.Fatalf("netpipetestserver: %s", err)
+	}
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	client.Transport.(*http.Transport).DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+		return network.dial(ctx, address)
+	}
+
+	return srv, client
+}
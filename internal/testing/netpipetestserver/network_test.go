@@ -0,0 +1,139 @@
+package netpipetestserver
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewServer(t *testing.T) {
+	network := NewNetwork(1)
+
+	_, upstream := NewServer(t, network, "upstream", "80", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello from upstream"))
+	}))
+	_ = upstream
+
+	downstreamSrv, downstream := NewServer(t, network, "downstream", "80", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://upstream:80/", http.StatusFound)
+	}))
+
+	resp, err := downstream.Get(downstreamSrv.URL)
+	if err != nil {
+		t.Fatalf("downstream.Get: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %s", err)
+	}
+	if got, want := string(body), "hello from upstream"; got != want {
+		t.Fatalf("got body %q, want %q", got, want)
+	}
+}
+
+func TestNetworkDialErrors(t *testing.T) {
+	network := NewNetwork(1)
+
+	if _, err := network.Host("h1").Dial("h1:80"); err == nil {
+		t.Fatal("expected an error dialing a port with no listener")
+	}
+	if _, err := network.Host("h1").Dial("nosuchhost:80"); err == nil {
+		t.Fatal("expected an error dialing an unknown host")
+	}
+
+	ln, err := network.Host("h1").Listen("80")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	defer ln.Close()
+
+	if _, err := network.Host("h1").Listen("80"); err == nil {
+		t.Fatal("expected an error re-listening on an in-use port")
+	}
+}
+
+func TestNetworkListenerClose(t *testing.T) {
+	network := NewNetwork(1)
+
+	ln, err := network.Host("h1").Listen("80")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+
+	if err := ln.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	if _, err := ln.Accept(); err != net.ErrClosed {
+		t.Fatalf("Accept after Close: got %v, want %v", err, net.ErrClosed)
+	}
+
+	if _, err := network.Host("h1").Dial("h1:80"); err == nil {
+		t.Fatal("expected an error dialing a closed, removed listener")
+	}
+
+	if _, err := network.Host("h1").Listen("80"); err != nil {
+		t.Fatalf("Listen on port vacated by Close: %s", err)
+	}
+}
+
+func TestNetworkAcceptQueueDepth(t *testing.T) {
+	const depth = 2
+
+	network := NewNetwork(depth)
+	ln, err := network.Host("h1").Listen("80")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	defer ln.Close()
+
+	// The first `depth` dials should buffer without an Accept to receive
+	// them.
+	buffered := make(chan error, depth)
+	for i := 0; i < depth; i++ {
+		go func() {
+			_, err := network.Host("h1").Dial("h1:80")
+			buffered <- err
+		}()
+	}
+	for i := 0; i < depth; i++ {
+		select {
+		case err := <-buffered:
+			if err != nil {
+				t.Fatalf("buffered Dial: %s", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Dial %d blocked despite accept queue depth %d", i, depth)
+		}
+	}
+
+	// A Dial beyond the queue depth should block until an Accept drains it.
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := network.Host("h1").Dial("h1:80")
+		blocked <- err
+	}()
+
+	select {
+	case err := <-blocked:
+		t.Fatalf("Dial beyond accept queue depth did not block, got err=%v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := ln.Accept(); err != nil {
+		t.Fatalf("Accept: %s", err)
+	}
+
+	select {
+	case err := <-blocked:
+		if err != nil {
+			t.Fatalf("Dial after drain: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Dial beyond accept queue depth never unblocked after Accept")
+	}
+}
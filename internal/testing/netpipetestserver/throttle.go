@@ -0,0 +1,218 @@
+package netpipetestserver
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock abstracts the passage of time used to apply latency, jitter and
+// bandwidth limits to a throttled connection, so that [NewWithOptions] can
+// be driven by a fake clock that advances virtual time instead of sleeping
+// in real wall-clock time, e.g. inside a synctest bubble. Within one
+// [NewWithOptions] server/client pair, Clock's Sleep is never called
+// concurrently: the server and client sides share one [Options], and calls
+// into its Clock are serialized internally. A Clock shared by several pairs
+// may still be called concurrently from different pairs.
+type Clock interface {
+	Sleep(d time.Duration)
+}
+
+// realClock is the default [Clock], backed by [time.Sleep].
+type realClock struct{}
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Options configures the throttling and fault injection [NewWithOptions]
+// applies to every conn accepted by the server and dialed by the client.
+type Options struct {
+	clock   Clock
+	clockMu sync.Mutex
+
+	readLatency, writeLatency time.Duration
+	readJitter, writeJitter   jitterRange
+	readBandwidth             int64 // bytes/sec, 0 means unlimited
+	writeBandwidth            int64 // bytes/sec, 0 means unlimited
+
+	errAfterBytes int64
+	err           error
+}
+
+type jitterRange struct {
+	min, max time.Duration
+}
+
+// Option configures an [Options] value.
+type Option func(*Options)
+
+// WithLatency adds a fixed delay to every Read and Write on both the server
+// and client side of the connection.
+func WithLatency(d time.Duration) Option {
+	return func(o *Options) {
+		o.readLatency = d
+		o.writeLatency = d
+	}
+}
+
+// WithJitter adds a random delay, uniformly distributed between min and
+// max, to every Read and Write, in addition to any delay from [WithLatency].
+func WithJitter(min, max time.Duration) Option {
+	return func(o *Options) {
+		o.readJitter = jitterRange{min, max}
+		o.writeJitter = jitterRange{min, max}
+	}
+}
+
+// WithBandwidth caps the throughput of every Read and Write to bps
+// bytes/sec.
+func WithBandwidth(bps int64) Option {
+	return func(o *Options) {
+		o.readBandwidth = bps
+		o.writeBandwidth = bps
+	}
+}
+
+// WithErrorAfter configures the connection to return err from every Read or
+// Write that completes once at least n bytes in total, counting both
+// directions together, have crossed the connection.
+func WithErrorAfter(n int64, err error) Option {
+	return func(o *Options) {
+		o.errAfterBytes = n
+		o.err = err
+	}
+}
+
+// WithClock overrides the [Clock] used to apply latency, jitter and
+// bandwidth limits, primarily so tests can drive a throttled connection
+// inside a synctest bubble without real sleeps.
+func WithClock(c Clock) Option {
+	return func(o *Options) { o.clock = c }
+}
+
+// NewWithOptions is a variant of [New] that wraps every conn accepted by the
+// server and dialed by the client in a throttled connection configured by
+// opts, simulating bandwidth limits, added latency and jitter, and error
+// injection, so tests can exercise slow- or lossy-network conditions
+// deterministically instead of relying on real wall-clock timing.
+func NewWithOptions(t *testing.T, handler http.Handler, opts ...Option) (*httptest.Server, *http.Client) {
+	t.Helper()
+
+	o := &Options{clock: realClock{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ln := newNetPipeListener()
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = &throttledListener{netPipeListener: ln, opts: o}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := ln.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return newThrottledConn(conn, o), nil
+	}
+
+	return srv, client
+}
+
+// throttledListener wraps a [netPipeListener], applying opts to every conn
+// it accepts.
+type throttledListener struct {
+	*netPipeListener
+	opts *Options
+}
+
+func (ln *throttledListener) Accept() (net.Conn, error) {
+	conn, err := ln.netPipeListener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newThrottledConn(conn, ln.opts), nil
+}
+
+// throttledConn wraps a [net.Conn], delaying and rate-limiting its Reads
+// and Writes and optionally injecting an error once a byte threshold is
+// crossed, as configured by an [Options] value.
+type throttledConn struct {
+	net.Conn
+	opts *Options
+
+	mu    sync.Mutex
+	total int64
+}
+
+func newThrottledConn(conn net.Conn, opts *Options) *throttledConn {
+	return &throttledConn{Conn: conn, opts: opts}
+}
+
+func (c *throttledConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.throttle(n, c.opts.readLatency, c.opts.readJitter, c.opts.readBandwidth)
+		if err == nil {
+			err = c.inject(n)
+		}
+	}
+	return n, err
+}
+
+func (c *throttledConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.throttle(n, c.opts.writeLatency, c.opts.writeJitter, c.opts.writeBandwidth)
+		if err == nil {
+			err = c.inject(n)
+		}
+	}
+	return n, err
+}
+
+// throttle sleeps for the delay accrued by transferring n bytes under the
+// given latency, jitter and bandwidth settings.
+func (c *throttledConn) throttle(n int, latency time.Duration, jitter jitterRange, bps int64) {
+	d := latency
+	switch {
+	case jitter.max > jitter.min:
+		d += jitter.min + time.Duration(rand.Int63n(int64(jitter.max-jitter.min)))
+	case jitter.max == jitter.min && jitter.max > 0:
+		d += jitter.min
+	}
+	if bps > 0 {
+		d += time.Duration(float64(n) / float64(bps) * float64(time.Second))
+	}
+	if d > 0 {
+		c.opts.clockMu.Lock()
+		c.opts.clock.Sleep(d)
+		c.opts.clockMu.Unlock()
+	}
+}
+
+// inject returns the configured error once enough bytes have crossed the
+// connection, and nil otherwise.
+func (c *throttledConn) inject(n int) error {
+	if c.opts.err == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total += int64(n)
+	if c.total >= c.opts.errAfterBytes {
+		return c.opts.err
+	}
+	return nil
+}
+
+var (
+	_ net.Listener = (*throttledListener)(nil)
+	_ net.Conn     = (*throttledConn)(nil)
+)
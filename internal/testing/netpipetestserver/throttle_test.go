@@ -0,0 +1,76 @@
+package netpipetestserver
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is the kind of naive clock a caller driving virtual time (e.g.
+// inside a synctest bubble) would write: it just accumulates elapsed
+// duration in a struct field. Its mutex lets the test read elapsed while a
+// connection may still be sleeping.
+type fakeClock struct {
+	mu      sync.Mutex
+	elapsed time.Duration
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	c.elapsed += d
+	c.mu.Unlock()
+}
+
+func TestNewWithOptions(t *testing.T) {
+	clock := &fakeClock{}
+	srv, client := NewWithOptions(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}), WithLatency(10*time.Millisecond), WithBandwidth(1<<20), WithClock(clock))
+
+	resp, err := client.Post(srv.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatalf("client.Post: %s", err)
+	}
+	resp.Body.Close()
+
+	clock.mu.Lock()
+	elapsed := clock.elapsed
+	clock.mu.Unlock()
+	if elapsed <= 0 {
+		t.Fatalf("expected clock to have accrued some elapsed time, got %s", elapsed)
+	}
+}
+
+func TestNewWithOptionsJitter(t *testing.T) {
+	clock := &fakeClock{}
+	srv, client := NewWithOptions(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}), WithJitter(5*time.Millisecond, 5*time.Millisecond), WithClock(clock))
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("client.Get: %s", err)
+	}
+	resp.Body.Close()
+
+	clock.mu.Lock()
+	elapsed := clock.elapsed
+	clock.mu.Unlock()
+	if elapsed < 5*time.Millisecond {
+		t.Fatalf("expected min==max jitter to add a constant delay, got elapsed %s", elapsed)
+	}
+}
+
+func TestNewWithOptionsErrorAfter(t *testing.T) {
+	wantErr := io.ErrClosedPipe
+	srv, client := NewWithOptions(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}), WithErrorAfter(1, wantErr))
+
+	_, err := client.Get(srv.URL)
+	if err == nil {
+		t.Fatal("expected an error from the throttled connection, got nil")
+	}
+}
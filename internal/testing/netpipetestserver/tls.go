@@ -0,0 +1,75 @@
+package netpipetestserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// NewTLS creates a new httptest.Server and http.Client pair that negotiate
+// TLS across an in-memory [net.Pipe] connection, using the server's
+// auto-generated certificate, so that TLS- and ALPN-dependent code paths can
+// be exercised inside a synctest bubble where [net.Pipe] alone won't do.
+func NewTLS(t *testing.T, handler http.Handler) (*httptest.Server, *http.Client) {
+	t.Helper()
+
+	ln := newNetPipeListener()
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = ln
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	transport := client.Transport.(*http.Transport)
+	// The dummy netPipeAddr used to reach the listener ("netpipetestserver:0")
+	// is never covered by the server's auto-generated certificate, so
+	// ordinary hostname verification can never succeed here; skip it, since
+	// this is a private in-memory pipe rather than a real network path.
+	tlsClientConfig := transport.TLSClientConfig.Clone()
+	tlsClientConfig.InsecureSkipVerify = true
+	transport.DialContext = ln.DialContext
+	transport.DialTLSContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := ln.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, tlsClientConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+
+	return srv, client
+}
+
+// NewH2C creates a new httptest.Server and http.Client pair speaking h2c
+// (HTTP/2 without TLS) across an in-memory [net.Pipe] connection, so that
+// HTTP/2-specific behavior can be exercised in a synctest bubble without a
+// real socket.
+func NewH2C(t *testing.T, handler http.Handler) (*httptest.Server, *http.Client) {
+	t.Helper()
+
+	ln := newNetPipeListener()
+	srv := httptest.NewUnstartedServer(h2c.NewHandler(handler, &http2.Server{}))
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	client.Transport = &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, address string, _ *tls.Config) (net.Conn, error) {
+			return ln.DialContext(ctx, network, address)
+		},
+	}
+
+	return srv, client
+}
@@ -0,0 +1,51 @@
+package netpipetestserver
+
+import (
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestNewTLS(t *testing.T) {
+	srv, client := NewTLS(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("client.Get: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %s", err)
+	}
+	if got := string(body); got != "ok" {
+		t.Fatalf("got body %q, want %q", got, "ok")
+	}
+}
+
+func TestNewH2C(t *testing.T) {
+	srv, client := NewH2C(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			http.Error(w, "expected HTTP/2", http.StatusHTTPVersionNotSupported)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("client.Get: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %s", err)
+	}
+	if got := string(body); got != "ok" {
+		t.Fatalf("got body %q, want %q", got, "ok")
+	}
+}
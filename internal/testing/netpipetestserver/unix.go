@@ -0,0 +1,92 @@
+package netpipetestserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// NewUnix creates a new httptest.Server and http.Client pair that
+// communicate over a Unix domain socket in a temporary directory, rather
+// than the fully-synchronous in-memory connection used by [New]. Use this
+// constructor instead of [New] for tests that depend on real FD semantics
+// (e.g. Hijack, deadlines, half-close via CloseWrite) that a [net.Pipe]
+// doesn't reproduce faithfully.
+func NewUnix(t *testing.T, handler http.Handler) (*httptest.Server, *http.Client) {
+	t.Helper()
+
+	ln := newUnixListener(t)
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	client.Transport.(*http.Transport).DialContext = ln.DialContext
+
+	return srv, client
+}
+
+// NewUnixTLS is the Unix domain socket equivalent of [httptest.NewTLSServer],
+// starting the server with TLS enabled over its own Unix domain socket, set
+// up the same way as the one [NewUnix] uses.
+func NewUnixTLS(t *testing.T, handler http.Handler) (*httptest.Server, *http.Client) {
+	t.Helper()
+
+	ln := newUnixListener(t)
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = ln
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	transport := client.Transport.(*http.Transport)
+	// The dummy unixListener address carries no hostname the server's
+	// auto-generated certificate could ever cover, so ordinary hostname
+	// verification can never succeed; skip it, since this is a private
+	// socket in a test-owned temp dir rather than a real network path.
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.DialContext = ln.DialContext
+
+	return srv, client
+}
+
+// unixListener is a [net.Listener] backed by a Unix domain socket in a
+// temporary directory, paired with a fixed dummy [net.Addr] so that
+// existing test code written against New's netPipeAddr-style plumbing (e.g.
+// [Dial]) continues to work unchanged.
+type unixListener struct {
+	net.Listener
+	path string
+	addr netPipeAddr
+}
+
+func newUnixListener(t *testing.T) *unixListener {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "netpipetestserver.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("netpipetestserver: failed to listen on %s: %s", path, err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	return &unixListener{Listener: ln, path: path}
+}
+
+func (ln *unixListener) Addr() net.Addr {
+	return ln.addr
+}
+
+// DialContext connects to the listener's underlying Unix domain socket,
+// ignoring the network and address arguments so that it may be used
+// directly as an [http.Transport]'s DialContext.
+func (ln *unixListener) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", ln.path)
+}
+
+var _ net.Listener = (*unixListener)(nil)
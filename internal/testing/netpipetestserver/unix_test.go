@@ -0,0 +1,47 @@
+package netpipetestserver
+
+import (
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestNewUnix(t *testing.T) {
+	srv, client := NewUnix(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("client.Get: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %s", err)
+	}
+	if got := string(body); got != "ok" {
+		t.Fatalf("got body %q, want %q", got, "ok")
+	}
+}
+
+func TestNewUnixTLS(t *testing.T) {
+	srv, client := NewUnixTLS(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("client.Get: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %s", err)
+	}
+	if got := string(body); got != "ok" {
+		t.Fatalf("got body %q, want %q", got, "ok")
+	}
+}